@@ -0,0 +1,144 @@
+package filesystem
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+// ChrootFS wraps a FileSystem, scoping every operation to a base
+// directory, similar to go-billy's NewOS(baseDir). It lets callers
+// compose an OSFS rooted at a workspace, a BufferFS rooted at a
+// prefix, or a test fixture tree, without duplicating join/clean
+// logic at every call site.
+type ChrootFS struct {
+	base string
+	fs   FileSystem
+}
+
+// NewChrootFS creates a ChrootFS rooted at base within fs
+func NewChrootFS(fs FileSystem, base string) *ChrootFS {
+	return &ChrootFS{base: path.Clean(base), fs: fs}
+}
+
+// resolve joins name under the chroot's base directory, rejecting
+// paths that escape it via ".." or an absolute override. name is
+// always interpreted as relative to the base, so an absolute name is
+// rejected outright rather than silently folded into the base. A
+// base of "." or "/" is the degenerate case of a chroot rooted at the
+// wrapped FileSystem's own root: nothing can escape it, but climbing
+// past the root via ".." is still rejected rather than clamped.
+func (c *ChrootFS) resolve(name string) (full string, e error) {
+	if path.IsAbs(name) {
+		e = &os.PathError{Op: "resolve", Path: name, Err: os.ErrPermission}
+		return
+	}
+	full = path.Join(c.base, name)
+	switch c.base {
+	case "/", ".":
+		rel := path.Clean(name)
+		if rel == ".." || strings.HasPrefix(rel, "../") {
+			e = &os.PathError{Op: "resolve", Path: name, Err: os.ErrPermission}
+		}
+		return
+	}
+	if full != c.base && !strings.HasPrefix(full, c.base+"/") {
+		e = &os.PathError{Op: "resolve", Path: name, Err: os.ErrPermission}
+	}
+	return
+}
+
+// Open opens a file relative to the chroot's base directory
+func (c *ChrootFS) Open(name string) (f File, e error) {
+	full, e := c.resolve(name)
+	if e != nil {
+		return
+	}
+	f, e = c.fs.Open(full)
+	return
+}
+
+// Stat stats a file relative to the chroot's base directory
+func (c *ChrootFS) Stat(name string) (f os.FileInfo, e error) {
+	full, e := c.resolve(name)
+	if e != nil {
+		return
+	}
+	f, e = c.fs.Stat(full)
+	return
+}
+
+// Create creates a file relative to the chroot's base directory
+func (c *ChrootFS) Create(name string) (f File, e error) {
+	full, e := c.resolve(name)
+	if e != nil {
+		return
+	}
+	f, e = c.fs.Create(full)
+	return
+}
+
+// Rename renames a file, both ends relative to the chroot's base
+// directory
+func (c *ChrootFS) Rename(old, new string) (e error) {
+	fullOld, e := c.resolve(old)
+	if e != nil {
+		return
+	}
+	fullNew, e := c.resolve(new)
+	if e != nil {
+		return
+	}
+	e = c.fs.Rename(fullOld, fullNew)
+	return
+}
+
+// ReadFile reads a file relative to the chroot's base directory
+func (c *ChrootFS) ReadFile(name string) (bs []byte, e error) {
+	full, e := c.resolve(name)
+	if e != nil {
+		return
+	}
+	bs, e = c.fs.ReadFile(full)
+	return
+}
+
+// ReadDir lists a directory relative to the chroot's base directory
+func (c *ChrootFS) ReadDir(name string) (fi []os.FileInfo, e error) {
+	full, e := c.resolve(name)
+	if e != nil {
+		return
+	}
+	fi, e = c.fs.ReadDir(full)
+	return
+}
+
+// OpenFile opens a file relative to the chroot's base directory,
+// auto-creating parent directories when os.O_CREATE is set
+func (c *ChrootFS) OpenFile(name string, flag int,
+	perm os.FileMode) (f File, e error) {
+	full, e := c.resolve(name)
+	if e != nil {
+		return
+	}
+	if flag&os.O_CREATE != 0 {
+		if e = c.fs.MkdirAll(path.Dir(full), 0755); e != nil {
+			return
+		}
+	}
+	f, e = c.fs.OpenFile(full, flag, perm)
+	return
+}
+
+// MkdirAll creates a directory relative to the chroot's base
+// directory, along with any necessary parents
+func (c *ChrootFS) MkdirAll(name string, perm os.FileMode) (e error) {
+	full, e := c.resolve(name)
+	if e != nil {
+		return
+	}
+	e = c.fs.MkdirAll(full, perm)
+	return
+}
+
+var _ FileSystem = (*ChrootFS)(nil)
@@ -0,0 +1,58 @@
+package filesystem_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/lamg/filesystem"
+)
+
+// escapeAttempts are names that must never resolve to anything
+// outside a ChrootFS's base, regardless of how the base is expressed
+var escapeAttempts = []string{"../x", "a/../../x", "..", "/etc/passwd"}
+
+func assertEscapesRejected(t *testing.T, label string, c *filesystem.ChrootFS) {
+	t.Helper()
+	for _, n := range escapeAttempts {
+		if _, e := c.Open(n); !errors.Is(e, os.ErrPermission) {
+			t.Errorf("%s: Open(%q): expected ErrPermission, got %v", label, n, e)
+		}
+		if _, e := c.Stat(n); !errors.Is(e, os.ErrPermission) {
+			t.Errorf("%s: Stat(%q): expected ErrPermission, got %v", label, n, e)
+		}
+		if e := c.Rename(n, "ok.txt"); !errors.Is(e, os.ErrPermission) {
+			t.Errorf("%s: Rename(%q, ok.txt): expected ErrPermission, got %v", label, n, e)
+		}
+		if e := c.Rename("ok.txt", n); !errors.Is(e, os.ErrPermission) {
+			t.Errorf("%s: Rename(ok.txt, %q): expected ErrPermission, got %v", label, n, e)
+		}
+		if _, e := c.OpenFile(n, os.O_RDONLY, 0644); !errors.Is(e, os.ErrPermission) {
+			t.Errorf("%s: OpenFile(%q): expected ErrPermission, got %v", label, n, e)
+		}
+	}
+}
+
+func TestChrootFSRejectsEscapesNonDegenerate(t *testing.T) {
+	bfs := filesystem.NewBufferFS()
+	if e := bfs.MkdirAll("sub/dir", 0755); e != nil {
+		t.Fatal(e)
+	}
+	assertEscapesRejected(t, `base="sub/dir"`, filesystem.NewChrootFS(bfs, "sub/dir"))
+}
+
+func TestChrootFSRejectsEscapesDegenerate(t *testing.T) {
+	for _, base := range []string{".", "/", ""} {
+		bfs := filesystem.NewBufferFS()
+		assertEscapesRejected(t, "base="+base, filesystem.NewChrootFS(bfs, base))
+	}
+}
+
+func TestChrootFSRejectsEscapesOnOSFS(t *testing.T) {
+	dir := t.TempDir()
+	osfs := &filesystem.OSFS{}
+	if e := osfs.MkdirAll(dir+"/sub", 0755); e != nil {
+		t.Fatal(e)
+	}
+	assertEscapesRejected(t, "OSFS base="+dir, filesystem.NewChrootFS(osfs, dir))
+}
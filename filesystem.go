@@ -1,11 +1,15 @@
 package filesystem
 
 import (
-	"bytes"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 // FileSystem abstracts the OS file system
@@ -15,6 +19,13 @@ type FileSystem interface {
 	Create(string) (File, error)
 	Rename(string, string) error
 	ReadFile(string) ([]byte, error)
+	// ReadDir reads the contents of the named directory
+	ReadDir(string) ([]os.FileInfo, error)
+	// OpenFile opens the named file with the given flag and
+	// permissions, creating it when os.O_CREATE is set
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	// MkdirAll creates a directory, along with any necessary parents
+	MkdirAll(path string, perm os.FileMode) error
 }
 
 // File abstracts a file system file
@@ -61,107 +72,441 @@ func (fs *OSFS) Create(name string) (f File, e error) {
 	return
 }
 
-// BufferFS implements FileSystem using in memory buffers
+// ReadDir reads the contents of a directory
+func (fs *OSFS) ReadDir(name string) (fi []os.FileInfo, e error) {
+	fi, e = ioutil.ReadDir(name)
+	return
+}
+
+// OpenFile opens a file with the given flag and permissions
+func (fs *OSFS) OpenFile(name string, flag int,
+	perm os.FileMode) (f File, e error) {
+	f, e = os.OpenFile(name, flag, perm)
+	return
+}
+
+// MkdirAll creates a directory, along with any necessary parents
+func (fs *OSFS) MkdirAll(name string, perm os.FileMode) (e error) {
+	e = os.MkdirAll(name, perm)
+	return
+}
+
+// BufferFS implements FileSystem using in memory files
 type BufferFS struct {
-	Bfs map[string]*BFile
+	mu  sync.Mutex
+	Bfs map[string]*bufEntry
 }
 
 // NewBufferFS creates a new BufferFS
 func NewBufferFS() (b *BufferFS) {
-	b = &BufferFS{make(map[string]*BFile)}
+	b = &BufferFS{Bfs: make(map[string]*bufEntry)}
 	return
 }
 
-// Open creates a new file in memory
+// Open opens an in memory file
 func (b *BufferFS) Open(name string) (f File, e error) {
-	var ok bool
-	f, ok = b.Bfs[name]
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.Bfs[name]
 	if !ok {
-		e = fmt.Errorf("Not found file %s", name)
+		e = &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		return
 	}
+	f = &BFile{e: entry}
 	return
 }
 
 // ReadFile reads the contents of a file
 func (b *BufferFS) ReadFile(name string) (bs []byte, e error) {
-	f, ok := b.Bfs[name]
-	if ok {
-		bs = f.Bytes()
-	} else {
-		e = fmt.Errorf("File %s doesn't exists", name)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.Bfs[name]
+	if !ok {
+		e = &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		return
 	}
+	bs = entry.bytes()
 	return
 }
 
-// GetBuffer gets the underlying
-func (b *BufferFS) GetBuffer(n string) (bf *bytes.Buffer,
-	ok bool) {
-	var f *BFile
-	f, ok = b.Bfs[n]
+// GetBuffer gets a copy of the underlying bytes of a file stored in b
+func (b *BufferFS) GetBuffer(n string) (bs []byte, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var entry *bufEntry
+	entry, ok = b.Bfs[n]
 	if ok {
-		bf = f.Buffer
+		bs = entry.bytes()
 	}
 	return
 }
 
-// Create creates a new file in memory
+// Create creates a new, empty in memory file, truncating it if it
+// already exists
 func (b *BufferFS) Create(name string) (f File, e error) {
-	b.Bfs[name] = NewBFile("")
-	f = b.Bfs[name]
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry := &bufEntry{name: name, mode: 0644, modTime: time.Now()}
+	b.Bfs[name] = entry
+	f = &BFile{e: entry}
 	return
 }
 
-// Rename renames a file
+// Rename renames a file, overwriting new if it already exists
 func (b *BufferFS) Rename(old, new string) (e error) {
-	f, ok := b.Bfs[old]
-	if ok {
-		delete(b.Bfs, old)
-		b.Bfs[new] = f
-	} else {
-		e = fmt.Errorf("File %s doesn't exists", old)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.Bfs[old]
+	if !ok {
+		e = &os.PathError{Op: "rename", Path: old, Err: os.ErrNotExist}
+		return
 	}
+	entry.mu.Lock()
+	entry.name = new
+	entry.modTime = time.Now()
+	entry.mu.Unlock()
+	delete(b.Bfs, old)
+	b.Bfs[new] = entry
 	return
 }
 
-// Stat stats an in memory file
+// Stat stats an in memory file or directory
 func (b *BufferFS) Stat(name string) (f os.FileInfo, e error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.statLocked(name)
+}
+
+// statLocked is Stat's implementation, assuming b.mu is already held
+func (b *BufferFS) statLocked(name string) (f os.FileInfo, e error) {
+	dir := path.Clean(name)
+	if entry, ok := b.Bfs[dir]; ok {
+		size, mode, modTime := entry.snapshot()
+		f = bufFileInfo{name: path.Base(dir), size: size, mode: mode, modTime: modTime}
+		return
+	}
+	if dir == "." || b.hasEntryUnder(dir) {
+		f = bufFileInfo{name: path.Base(dir), mode: os.ModeDir}
+		return
+	}
+	e = &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	return
+}
+
+// hasEntryUnder reports whether any stored file has dir as an
+// ancestor directory. Assumes b.mu is already held.
+func (b *BufferFS) hasEntryUnder(dir string) bool {
+	prefix := dir + "/"
+	for n := range b.Bfs {
+		if strings.HasPrefix(path.Clean(n), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadDir lists the names stored directly below name, modeling
+// intermediate directories implied by the stored paths
+func (b *BufferFS) ReadDir(name string) (fi []os.FileInfo, e error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	dir := path.Clean(name)
+	if dir != "." {
+		info, e2 := b.statLocked(dir)
+		if e2 != nil {
+			e = e2
+			return
+		}
+		if !info.IsDir() {
+			e = &os.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+			return
+		}
+	}
+	seen := make(map[string]os.FileInfo)
+	for n, entry := range b.Bfs {
+		cp := path.Clean(n)
+		rel := cp
+		if dir != "." {
+			prefix := dir + "/"
+			if !strings.HasPrefix(cp, prefix) {
+				continue
+			}
+			rel = strings.TrimPrefix(cp, prefix)
+		}
+		parts := strings.SplitN(rel, "/", 2)
+		base := parts[0]
+		if len(parts) == 1 {
+			// an explicit entry always wins over a synthesized one,
+			// regardless of map iteration order
+			size, mode, modTime := entry.snapshot()
+			seen[base] = bufFileInfo{name: base, size: size, mode: mode, modTime: modTime}
+			continue
+		}
+		if _, ok := seen[base]; !ok {
+			seen[base] = bufFileInfo{name: base, mode: os.ModeDir}
+		}
+	}
+	for _, info := range seen {
+		fi = append(fi, info)
+	}
+	sort.Slice(fi, func(i, j int) bool { return fi[i].Name() < fi[j].Name() })
+	return
+}
+
+// OpenFile opens or creates an in memory file depending on flag
+func (b *BufferFS) OpenFile(name string, flag int,
+	perm os.FileMode) (f File, e error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.Bfs[name]
+	switch {
+	case ok && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		e = &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+		return
+	case !ok && flag&os.O_CREATE != 0:
+		entry = &bufEntry{name: name, mode: perm, modTime: time.Now()}
+		b.Bfs[name] = entry
+	case !ok:
+		e = &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		return
+	case flag&os.O_TRUNC != 0:
+		entry.mu.Lock()
+		entry.data = nil
+		entry.modTime = time.Now()
+		entry.mu.Unlock()
+	}
+	bf := &BFile{e: entry}
+	if flag&os.O_APPEND != 0 {
+		bf.offset, _, _ = entry.snapshot()
+	}
+	f = bf
+	return
+}
+
+// MkdirAll creates a directory, along with any necessary parents,
+// recording each as an explicit empty directory entry
+func (b *BufferFS) MkdirAll(name string, perm os.FileMode) (e error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	dir := path.Clean(name)
+	if dir == "." || dir == "/" {
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(dir, "/"), "/")
+	acc := ""
+	if path.IsAbs(dir) {
+		acc = "/"
+	}
+	for _, p := range parts {
+		acc = path.Join(acc, p)
+		if entry, ok := b.Bfs[acc]; ok {
+			if _, mode, _ := entry.snapshot(); mode&os.ModeDir == 0 {
+				e = &os.PathError{Op: "mkdir", Path: acc, Err: os.ErrExist}
+				return
+			}
+			continue
+		}
+		b.Bfs[acc] = &bufEntry{name: acc, mode: os.ModeDir | perm, modTime: time.Now()}
+	}
 	return
 }
 
-// BFile is a file stored in memory as a *bytes.Buffer
+// bufEntry is the data shared by every open handle onto the same
+// BufferFS path. Its fields are mutated through independently opened
+// BFile handles as well as through BufferFS itself (e.g. Rename), so
+// every access goes through mu.
+type bufEntry struct {
+	mu      sync.Mutex
+	name    string
+	data    []byte
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// snapshot returns the entry's size, mode and modTime as of the call
+func (e *bufEntry) snapshot() (size int64, mode os.FileMode, modTime time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return int64(len(e.data)), e.mode, e.modTime
+}
+
+// bytes returns a copy of the entry's data
+func (e *bufEntry) bytes() []byte {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]byte(nil), e.data...)
+}
+
+// lockedName returns the entry's current name
+func (e *bufEntry) lockedName() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.name
+}
+
+// bufFileInfo is an os.FileInfo for entries reported by BufferFS
+type bufFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (i bufFileInfo) Name() string       { return i.name }
+func (i bufFileInfo) Size() int64        { return i.size }
+func (i bufFileInfo) Mode() os.FileMode  { return i.mode }
+func (i bufFileInfo) ModTime() time.Time { return i.modTime }
+func (i bufFileInfo) IsDir() bool        { return i.mode&os.ModeDir != 0 }
+func (i bufFileInfo) Sys() interface{}   { return nil }
+
+// BFile is an open handle onto an in memory file, with its own
+// independent read/write offset
 type BFile struct {
-	*bytes.Buffer
+	e      *bufEntry
+	offset int64
+	closed bool
 }
 
-// NewBFile creates a new BFile
+// NewBFile creates a detached BFile holding content, not registered
+// in any BufferFS
 func NewBFile(content string) (b *BFile) {
-	b = &BFile{bytes.NewBufferString(content)}
+	b = &BFile{e: &bufEntry{data: []byte(content), modTime: time.Now()}}
 	return
 }
 
-// Close closes the BFile
+// Close closes the BFile, making it unusable for further reads or
+// writes. Close is idempotent: closing an already closed BFile is a
+// no-op
 func (b *BFile) Close() (e error) {
-	// TODO? b.bf.Reset()
-	e = fmt.Errorf("Not implemented")
+	b.closed = true
 	return
 }
 
 // Stat stats the BFile
 func (b *BFile) Stat() (f os.FileInfo, e error) {
-	// TODO
-	e = fmt.Errorf("Not implemented")
+	if b.closed {
+		e = os.ErrClosed
+		return
+	}
+	b.e.mu.Lock()
+	defer b.e.mu.Unlock()
+	f = bufFileInfo{name: path.Base(b.e.name), size: int64(len(b.e.data)),
+		mode: b.e.mode, modTime: b.e.modTime}
+	return
+}
+
+// Read implementation of io.Reader
+func (b *BFile) Read(p []byte) (n int, e error) {
+	if b.closed {
+		e = os.ErrClosed
+		return
+	}
+	b.e.mu.Lock()
+	defer b.e.mu.Unlock()
+	if b.offset >= int64(len(b.e.data)) {
+		e = io.EOF
+		return
+	}
+	n = copy(p, b.e.data[b.offset:])
+	b.offset += int64(n)
+	return
+}
+
+// Write implementation of io.Writer
+func (b *BFile) Write(p []byte) (n int, e error) {
+	if b.closed {
+		e = os.ErrClosed
+		return
+	}
+	b.e.mu.Lock()
+	defer b.e.mu.Unlock()
+	end := b.offset + int64(len(p))
+	b.grow(end)
+	n = copy(b.e.data[b.offset:end], p)
+	b.offset += int64(n)
+	b.e.modTime = time.Now()
 	return
 }
 
 // ReadAt implementation of io.ReaderAt
 func (b *BFile) ReadAt(p []byte, off int64) (n int, e error) {
-	e = fmt.Errorf("Not implemented")
+	if b.closed {
+		e = os.ErrClosed
+		return
+	}
+	if off < 0 {
+		e = &os.PathError{Op: "readat", Path: b.e.lockedName(), Err: os.ErrInvalid}
+		return
+	}
+	b.e.mu.Lock()
+	defer b.e.mu.Unlock()
+	if off >= int64(len(b.e.data)) {
+		e = io.EOF
+		return
+	}
+	n = copy(p, b.e.data[off:])
+	if n < len(p) {
+		e = io.EOF
+	}
+	return
+}
+
+// WriteAt implementation of io.WriterAt
+func (b *BFile) WriteAt(p []byte, off int64) (n int, e error) {
+	if b.closed {
+		e = os.ErrClosed
+		return
+	}
+	if off < 0 {
+		e = &os.PathError{Op: "writeat", Path: b.e.lockedName(), Err: os.ErrInvalid}
+		return
+	}
+	b.e.mu.Lock()
+	defer b.e.mu.Unlock()
+	end := off + int64(len(p))
+	b.grow(end)
+	n = copy(b.e.data[off:end], p)
+	b.e.modTime = time.Now()
 	return
 }
 
 // Seek implementation of io.Seeker
 func (b *BFile) Seek(offset int64,
 	whence int) (n int64, e error) {
-	e = fmt.Errorf("Not implemented")
+	if b.closed {
+		e = os.ErrClosed
+		return
+	}
+	b.e.mu.Lock()
+	defer b.e.mu.Unlock()
+	abs := offset
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		abs += b.offset
+	case io.SeekEnd:
+		abs += int64(len(b.e.data))
+	default:
+		e = &os.PathError{Op: "seek", Path: b.e.name, Err: os.ErrInvalid}
+		return
+	}
+	if abs < 0 {
+		e = &os.PathError{Op: "seek", Path: b.e.name, Err: os.ErrInvalid}
+		return
+	}
+	b.offset = abs
+	n = abs
 	return
 }
+
+// grow extends the underlying data slice so that size bytes are
+// addressable, preserving existing content. Callers must hold
+// b.e.mu.
+func (b *BFile) grow(size int64) {
+	if size <= int64(len(b.e.data)) {
+		return
+	}
+	grown := make([]byte, size)
+	copy(grown, b.e.data)
+	b.e.data = grown
+}
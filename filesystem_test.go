@@ -0,0 +1,29 @@
+package filesystem_test
+
+import (
+	"testing"
+
+	"github.com/lamg/filesystem"
+	"github.com/lamg/filesystem/fstest"
+)
+
+func TestOSFSConformance(t *testing.T) {
+	fstest.RunSuite(t, func() filesystem.FileSystem {
+		return filesystem.NewChrootFS(&filesystem.OSFS{}, t.TempDir())
+	})
+}
+
+func TestBufferFSConformance(t *testing.T) {
+	fstest.RunSuite(t, func() filesystem.FileSystem {
+		return filesystem.NewBufferFS()
+	})
+}
+
+// TestChrootFSRootConformance exercises the degenerate case of a
+// ChrootFS rooted at "" (cleaned to "."), which never goes through
+// the t.TempDir()-backed OSFS case above
+func TestChrootFSRootConformance(t *testing.T) {
+	fstest.RunSuite(t, func() filesystem.FileSystem {
+		return filesystem.NewChrootFS(filesystem.NewBufferFS(), "")
+	})
+}
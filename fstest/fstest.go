@@ -0,0 +1,348 @@
+// Package fstest exercises the filesystem.FileSystem contract
+// against a concrete implementation, mirroring the conformance suite
+// go-billy extracted as utils/fs/test. It is the entry point third
+// party FileSystem implementations should run against to check they
+// behave like OSFS and BufferFS.
+package fstest
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/lamg/filesystem"
+)
+
+// RunSuite exercises the full FileSystem contract against instances
+// produced by factory, which must return a distinct, empty
+// filesystem.FileSystem on every call
+func RunSuite(t *testing.T, factory func() filesystem.FileSystem) {
+	t.Run("CreateWriteReadRoundTrip", func(t *testing.T) {
+		testCreateWriteRead(t, factory())
+	})
+	t.Run("SeekAndReadAt", func(t *testing.T) {
+		testSeekReadAt(t, factory())
+	})
+	t.Run("Rename", func(t *testing.T) {
+		testRename(t, factory())
+	})
+	t.Run("RenameOverwrite", func(t *testing.T) {
+		testRenameOverwrite(t, factory())
+	})
+	t.Run("RenameCrossDirectory", func(t *testing.T) {
+		testRenameCrossDir(t, factory())
+	})
+	t.Run("Stat", func(t *testing.T) {
+		testStat(t, factory())
+	})
+	t.Run("NotFoundAndAlreadyExists", func(t *testing.T) {
+		testErrorIdentity(t, factory())
+	})
+	t.Run("ConcurrentOpen", func(t *testing.T) {
+		testConcurrentOpen(t, factory())
+	})
+	t.Run("ConcurrentWriteAt", func(t *testing.T) {
+		testConcurrentWriteAt(t, factory())
+	})
+	t.Run("ReadDirOpenFileMkdirAll", func(t *testing.T) {
+		testDirOps(t, factory())
+	})
+}
+
+func testCreateWriteRead(t *testing.T, fs filesystem.FileSystem) {
+	f, e := fs.Create("a.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = f.Write([]byte("hello world")); e != nil {
+		t.Fatal(e)
+	}
+	if e = f.Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	bs, e := fs.ReadFile("a.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if string(bs) != "hello world" {
+		t.Fatalf("ReadFile: got %q", bs)
+	}
+
+	f2, e := fs.Open("a.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer f2.Close()
+	got, e := io.ReadAll(f2)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if string(got) != "hello world" {
+		t.Fatalf("Open+Read: got %q", got)
+	}
+}
+
+func testSeekReadAt(t *testing.T, fs filesystem.FileSystem) {
+	f, e := fs.Create("seek.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer f.Close()
+	if _, e = f.Write([]byte("0123456789")); e != nil {
+		t.Fatal(e)
+	}
+
+	n, e := f.Seek(3, io.SeekStart)
+	if e != nil || n != 3 {
+		t.Fatalf("Seek: n=%d e=%v", n, e)
+	}
+	buf := make([]byte, 4)
+	if nr, e := f.Read(buf); e != nil || nr != 4 || string(buf) != "3456" {
+		t.Fatalf("Read after Seek: nr=%d e=%v buf=%q", nr, e, buf)
+	}
+
+	buf2 := make([]byte, 3)
+	nr2, e := f.ReadAt(buf2, 7)
+	if e != nil && e != io.EOF {
+		t.Fatal(e)
+	}
+	if nr2 != 3 || string(buf2) != "789" {
+		t.Fatalf("ReadAt: nr=%d buf=%q", nr2, buf2)
+	}
+}
+
+func testRename(t *testing.T, fs filesystem.FileSystem) {
+	f, e := fs.Create("old.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	f.Write([]byte("data"))
+	f.Close()
+
+	if e = fs.Rename("old.txt", "new.txt"); e != nil {
+		t.Fatal(e)
+	}
+	if _, e = fs.Stat("old.txt"); !errors.Is(e, os.ErrNotExist) {
+		t.Fatalf("Stat(old): expected ErrNotExist, got %v", e)
+	}
+	bs, e := fs.ReadFile("new.txt")
+	if e != nil || string(bs) != "data" {
+		t.Fatalf("ReadFile(new): e=%v bs=%q", e, bs)
+	}
+}
+
+func testRenameOverwrite(t *testing.T, fs filesystem.FileSystem) {
+	a, _ := fs.Create("a.txt")
+	a.Write([]byte("a"))
+	a.Close()
+	b, _ := fs.Create("b.txt")
+	b.Write([]byte("b"))
+	b.Close()
+
+	if e := fs.Rename("a.txt", "b.txt"); e != nil {
+		t.Fatal(e)
+	}
+	bs, e := fs.ReadFile("b.txt")
+	if e != nil || string(bs) != "a" {
+		t.Fatalf("e=%v bs=%q", e, bs)
+	}
+}
+
+func testRenameCrossDir(t *testing.T, fs filesystem.FileSystem) {
+	if e := fs.MkdirAll("dir1", 0755); e != nil {
+		t.Fatal(e)
+	}
+	if e := fs.MkdirAll("dir2", 0755); e != nil {
+		t.Fatal(e)
+	}
+	f, e := fs.Create("dir1/f.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	f.Write([]byte("cross"))
+	f.Close()
+
+	if e := fs.Rename("dir1/f.txt", "dir2/f.txt"); e != nil {
+		t.Fatal(e)
+	}
+	bs, e := fs.ReadFile("dir2/f.txt")
+	if e != nil || string(bs) != "cross" {
+		t.Fatalf("e=%v bs=%q", e, bs)
+	}
+}
+
+func testStat(t *testing.T, fs filesystem.FileSystem) {
+	f, e := fs.Create("stat.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	f.Write([]byte("12345"))
+	f.Close()
+
+	fi, e := fs.Stat("stat.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if fi.Name() != "stat.txt" {
+		t.Fatalf("Name: got %q", fi.Name())
+	}
+	if fi.Size() != 5 {
+		t.Fatalf("Size: got %d", fi.Size())
+	}
+	if fi.IsDir() {
+		t.Fatal("IsDir: file reported as directory")
+	}
+}
+
+func testErrorIdentity(t *testing.T, fs filesystem.FileSystem) {
+	if _, e := fs.Open("missing.txt"); !errors.Is(e, os.ErrNotExist) {
+		t.Fatalf("Open: expected ErrNotExist, got %v", e)
+	}
+	if _, e := fs.Stat("missing.txt"); !errors.Is(e, os.ErrNotExist) {
+		t.Fatalf("Stat: expected ErrNotExist, got %v", e)
+	}
+	if _, e := fs.ReadFile("missing.txt"); !errors.Is(e, os.ErrNotExist) {
+		t.Fatalf("ReadFile: expected ErrNotExist, got %v", e)
+	}
+	if e := fs.Rename("missing.txt", "x.txt"); !errors.Is(e, os.ErrNotExist) {
+		t.Fatalf("Rename: expected ErrNotExist, got %v", e)
+	}
+
+	f, e := fs.Create("exists.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	f.Close()
+	if _, e = fs.OpenFile("exists.txt", os.O_CREATE|os.O_EXCL, 0644); !errors.Is(e, os.ErrExist) {
+		t.Fatalf("OpenFile: expected ErrExist, got %v", e)
+	}
+}
+
+func testConcurrentOpen(t *testing.T, fs filesystem.FileSystem) {
+	f, e := fs.Create("shared.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	f.Write([]byte("shared content"))
+	f.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h, e := fs.Open("shared.txt")
+			if e != nil {
+				errs <- e
+				return
+			}
+			defer h.Close()
+			if _, e = io.ReadAll(h); e != nil {
+				errs <- e
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Fatal(e)
+	}
+}
+
+// testConcurrentWriteAt opens the same path through several
+// independent handles and writes to disjoint regions concurrently,
+// checking that the implementation synchronizes access to the shared
+// backing data rather than just to its directory/lookup state
+func testConcurrentWriteAt(t *testing.T, fs filesystem.FileSystem) {
+	const handles = 8
+	const chunk = 16
+
+	f, e := fs.Create("concurrent.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = f.Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, handles)
+	for i := 0; i < handles; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			h, e := fs.OpenFile("concurrent.txt", os.O_RDWR, 0644)
+			if e != nil {
+				errs <- e
+				return
+			}
+			defer h.Close()
+			wa, ok := h.(io.WriterAt)
+			if !ok {
+				errs <- errors.New("File does not implement io.WriterAt")
+				return
+			}
+			buf := make([]byte, chunk)
+			for j := range buf {
+				buf[j] = byte('a' + i)
+			}
+			if _, e = wa.WriteAt(buf, int64(i*chunk)); e != nil {
+				errs <- e
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Fatal(e)
+	}
+
+	bs, e := fs.ReadFile("concurrent.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(bs) != handles*chunk {
+		t.Fatalf("got length %d, want %d", len(bs), handles*chunk)
+	}
+	for i := 0; i < handles; i++ {
+		want := byte('a' + i)
+		for j := 0; j < chunk; j++ {
+			if got := bs[i*chunk+j]; got != want {
+				t.Fatalf("byte %d = %q, want %q", i*chunk+j, got, want)
+			}
+		}
+	}
+}
+
+func testDirOps(t *testing.T, fs filesystem.FileSystem) {
+	if e := fs.MkdirAll("a/b/c", 0755); e != nil {
+		t.Fatal(e)
+	}
+
+	f, e := fs.OpenFile("a/b/c/f.txt", os.O_CREATE|os.O_WRONLY, 0644)
+	if e != nil {
+		t.Fatal(e)
+	}
+	f.Write([]byte("nested"))
+	f.Close()
+
+	fis, e := fs.ReadDir("a/b/c")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(fis) != 1 || fis[0].Name() != "f.txt" {
+		t.Fatalf("ReadDir(a/b/c): %+v", fis)
+	}
+
+	fis2, e := fs.ReadDir("a")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(fis2) != 1 || fis2[0].Name() != "b" || !fis2[0].IsDir() {
+		t.Fatalf("ReadDir(a): %+v", fis2)
+	}
+}
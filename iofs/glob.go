@@ -0,0 +1,75 @@
+package iofs
+
+import (
+	"path"
+	"strings"
+)
+
+// Glob implements fs.GlobFS, following the same algorithm as
+// io/fs.Glob so it behaves identically whether or not the underlying
+// FileSystem is glob-aware.
+func (a *FS) Glob(pattern string) (matches []string, e error) {
+	if _, e = path.Match(pattern, ""); e != nil {
+		return nil, e
+	}
+	if !hasMeta(pattern) {
+		if _, e = a.Stat(pattern); e != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	dir, file := path.Split(pattern)
+	dir = cleanGlobDir(dir)
+
+	if !hasMeta(dir) {
+		return a.glob(dir, file, nil)
+	}
+
+	var dirs []string
+	dirs, e = a.Glob(dir)
+	if e != nil {
+		return nil, e
+	}
+	for _, d := range dirs {
+		matches, e = a.glob(d, file, matches)
+		if e != nil {
+			return nil, e
+		}
+	}
+	return matches, nil
+}
+
+func cleanGlobDir(dir string) string {
+	switch dir {
+	case "":
+		return "."
+	case "/":
+		return dir
+	default:
+		return dir[:len(dir)-1]
+	}
+}
+
+// glob appends to matches the names of files in dir that match pattern
+func (a *FS) glob(dir, pattern string, matches []string) ([]string, error) {
+	es, e := a.ReadDir(dir)
+	if e != nil {
+		return matches, nil
+	}
+	for _, d := range es {
+		name := d.Name()
+		ok, e := path.Match(pattern, name)
+		if e != nil {
+			return matches, e
+		}
+		if ok {
+			matches = append(matches, path.Join(dir, name))
+		}
+	}
+	return matches, nil
+}
+
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[\\")
+}
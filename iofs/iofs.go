@@ -0,0 +1,259 @@
+// Package iofs adapts a filesystem.FileSystem into the standard
+// library's io/fs.FS, so existing implementations (OSFS, BufferFS, or
+// any third party type) can be used wherever an fs.FS is expected, and
+// checked for correctness with fstest.TestFS.
+package iofs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/lamg/filesystem"
+)
+
+// FS adapts a filesystem.FileSystem to io/fs.FS, io/fs.ReadDirFS,
+// io/fs.StatFS, io/fs.ReadFileFS, io/fs.GlobFS and io/fs.SubFS.
+type FS struct {
+	under filesystem.FileSystem
+}
+
+// New wraps fsys as an io/fs.FS
+func New(fsys filesystem.FileSystem) *FS {
+	return &FS{under: fsys}
+}
+
+var (
+	_ fs.FS         = (*FS)(nil)
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+	_ fs.GlobFS     = (*FS)(nil)
+	_ fs.SubFS      = (*FS)(nil)
+)
+
+// Open implements fs.FS
+func (a *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return a.openDir(name)
+	}
+	f, e := a.under.Open(name)
+	if e != nil {
+		if fi, serr := a.under.Stat(name); serr == nil && fi.IsDir() {
+			return a.openDir(name)
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: e}
+	}
+	if fi, e := f.Stat(); e == nil && fi.IsDir() {
+		f.Close()
+		return a.openDir(name)
+	}
+	return &file{File: f, name: name}, nil
+}
+
+// ReadDir implements fs.ReadDirFS
+func (a *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	fi, e := a.under.ReadDir(name)
+	if e != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: e}
+	}
+	es := make([]fs.DirEntry, len(fi))
+	for i, f := range fi {
+		es[i] = fs.FileInfoToDirEntry(f)
+	}
+	sort.Slice(es, func(i, j int) bool { return es[i].Name() < es[j].Name() })
+	return es, nil
+}
+
+// Stat implements fs.StatFS
+func (a *FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	fi, e := a.under.Stat(name)
+	if e != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: e}
+	}
+	return fi, nil
+}
+
+// ReadFile implements fs.ReadFileFS
+func (a *FS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	bs, e := a.under.ReadFile(name)
+	if e != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: e}
+	}
+	return bs, nil
+}
+
+// Sub implements fs.SubFS
+func (a *FS) Sub(dir string) (fs.FS, error) {
+	if dir == "." {
+		return a, nil
+	}
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+	if fi, e := a.under.Stat(dir); e != nil || !fi.IsDir() {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: os.ErrNotExist}
+	}
+	return &subFS{FS: a, prefix: dir}, nil
+}
+
+func (a *FS) openDir(name string) (fs.File, error) {
+	fi, e := a.under.Stat(name)
+	if e != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: e}
+	}
+	es, e := a.ReadDir(name)
+	if e != nil {
+		return nil, e
+	}
+	return &dirFile{name: name, info: fi, entries: es}, nil
+}
+
+// file adapts a filesystem.File into an fs.File, translating errors
+// into *fs.PathError as required by the io/fs contract
+type file struct {
+	filesystem.File
+	name string
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	fi, e := f.File.Stat()
+	if e != nil {
+		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: e}
+	}
+	return fi, nil
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	n, e := f.File.Read(p)
+	if e != nil && !errors.Is(e, io.EOF) {
+		e = &fs.PathError{Op: "read", Path: f.name, Err: e}
+	}
+	return n, e
+}
+
+func (f *file) Close() error {
+	if e := f.File.Close(); e != nil {
+		return &fs.PathError{Op: "close", Path: f.name, Err: e}
+	}
+	return nil
+}
+
+// dirFile implements fs.ReadDirFile over a pre-read set of entries
+type dirFile struct {
+	name    string
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dirFile) Close() error { return nil }
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
+
+// subFS roots an FS at prefix, as returned by Sub
+type subFS struct {
+	*FS
+	prefix string
+}
+
+func (s *subFS) full(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return s.prefix, nil
+	}
+	return path.Join(s.prefix, name), nil
+}
+
+func (s *subFS) Open(name string) (fs.File, error) {
+	full, e := s.full(name)
+	if e != nil {
+		return nil, e
+	}
+	return s.FS.Open(full)
+}
+
+func (s *subFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	full, e := s.full(name)
+	if e != nil {
+		return nil, e
+	}
+	return s.FS.ReadDir(full)
+}
+
+func (s *subFS) Stat(name string) (fs.FileInfo, error) {
+	full, e := s.full(name)
+	if e != nil {
+		return nil, e
+	}
+	return s.FS.Stat(full)
+}
+
+func (s *subFS) ReadFile(name string) ([]byte, error) {
+	full, e := s.full(name)
+	if e != nil {
+		return nil, e
+	}
+	return s.FS.ReadFile(full)
+}
+
+func (s *subFS) Glob(pattern string) ([]string, error) {
+	if !fs.ValidPath(pattern) {
+		return nil, &fs.PathError{Op: "glob", Path: pattern, Err: fs.ErrInvalid}
+	}
+	full := path.Join(s.prefix, pattern)
+	ms, e := s.FS.Glob(full)
+	if e != nil {
+		return nil, e
+	}
+	for i, m := range ms {
+		ms[i] = strings.TrimPrefix(strings.TrimPrefix(m, s.prefix), "/")
+	}
+	return ms, nil
+}
+
+func (s *subFS) Sub(dir string) (fs.FS, error) {
+	full, e := s.full(dir)
+	if e != nil {
+		return nil, e
+	}
+	return s.FS.Sub(full)
+}
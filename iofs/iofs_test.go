@@ -0,0 +1,78 @@
+package iofs_test
+
+import (
+	"testing"
+	stdfstest "testing/fstest"
+
+	"github.com/lamg/filesystem"
+	"github.com/lamg/filesystem/iofs"
+)
+
+func TestBufferFSSatisfiesIOFS(t *testing.T) {
+	under := filesystem.NewBufferFS()
+	seed := map[string]string{
+		"a.txt":        "hello",
+		"dir/b.txt":    "world",
+		"dir/sub/c.go": "package sub",
+	}
+	for name, content := range seed {
+		if e := under.MkdirAll(dirOf(name), 0755); e != nil {
+			t.Fatal(e)
+		}
+		f, e := under.Create(name)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if _, e = f.Write([]byte(content)); e != nil {
+			t.Fatal(e)
+		}
+		if e = f.Close(); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	fsys := iofs.New(under)
+	if e := stdfstest.TestFS(fsys, "a.txt", "dir/b.txt", "dir/sub/c.go"); e != nil {
+		t.Fatal(e)
+	}
+}
+
+func TestOSFSSatisfiesIOFS(t *testing.T) {
+	under := filesystem.NewChrootFS(&filesystem.OSFS{}, t.TempDir())
+	seed := map[string]string{
+		"a.txt":        "hello",
+		"dir/b.txt":    "world",
+		"dir/sub/c.go": "package sub",
+	}
+	for name, content := range seed {
+		if e := under.MkdirAll(dirOf(name), 0755); e != nil {
+			t.Fatal(e)
+		}
+		f, e := under.Create(name)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if _, e = f.Write([]byte(content)); e != nil {
+			t.Fatal(e)
+		}
+		if e = f.Close(); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	fsys := iofs.New(under)
+	if e := stdfstest.TestFS(fsys, "a.txt", "dir/b.txt", "dir/sub/c.go"); e != nil {
+		t.Fatal(e)
+	}
+}
+
+// dirOf returns the parent directory of a slash-separated path, or "."
+// if it has none
+func dirOf(name string) string {
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '/' {
+			return name[:i]
+		}
+	}
+	return "."
+}
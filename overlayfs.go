@@ -0,0 +1,228 @@
+package filesystem
+
+import (
+	"os"
+	"path"
+	"sort"
+)
+
+// OverlayFS implements FileSystem by layering a read-write upper
+// FileSystem (typically a BufferFS) over a read-only lower one
+// (typically an OSFS). Reads fall through to the lower layer when the
+// upper has no entry; writes, creates and renames only ever touch the
+// upper, copying a file up from the lower on first write. This lets
+// tests stage modifications on top of a real directory without
+// touching disk.
+type OverlayFS struct {
+	upper    FileSystem
+	lower    FileSystem
+	whiteout map[string]bool
+}
+
+// NewOverlayFS creates an OverlayFS with upper as the read-write layer
+// and lower as the read-only layer
+func NewOverlayFS(upper, lower FileSystem) *OverlayFS {
+	return &OverlayFS{upper: upper, lower: lower, whiteout: make(map[string]bool)}
+}
+
+// Open opens name from the upper layer, falling through to the lower
+// layer unless name has been whited out
+func (o *OverlayFS) Open(name string) (f File, e error) {
+	if f, e = o.upper.Open(name); e == nil {
+		return
+	}
+	if o.whiteout[name] {
+		e = &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		return
+	}
+	return o.lower.Open(name)
+}
+
+// Stat stats name from the upper layer, falling through to the lower
+// layer unless name has been whited out
+func (o *OverlayFS) Stat(name string) (fi os.FileInfo, e error) {
+	if fi, e = o.upper.Stat(name); e == nil {
+		return
+	}
+	if o.whiteout[name] {
+		e = &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+		return
+	}
+	return o.lower.Stat(name)
+}
+
+// ReadFile reads name from the upper layer, falling through to the
+// lower layer unless name has been whited out
+func (o *OverlayFS) ReadFile(name string) (bs []byte, e error) {
+	if bs, e = o.upper.ReadFile(name); e == nil {
+		return
+	}
+	if o.whiteout[name] {
+		e = &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		return
+	}
+	return o.lower.ReadFile(name)
+}
+
+// ReadDir merges the upper and lower listings of name, upper entries
+// taking precedence and whited out names excluded
+func (o *OverlayFS) ReadDir(name string) (fi []os.FileInfo, e error) {
+	seen := make(map[string]os.FileInfo)
+	upperFi, upperErr := o.upper.ReadDir(name)
+	for _, f := range upperFi {
+		seen[f.Name()] = f
+	}
+	lowerFi, lowerErr := o.lower.ReadDir(name)
+	for _, f := range lowerFi {
+		if o.whiteout[path.Join(name, f.Name())] {
+			continue
+		}
+		if _, ok := seen[f.Name()]; !ok {
+			seen[f.Name()] = f
+		}
+	}
+	if upperErr != nil && lowerErr != nil {
+		e = lowerErr
+		return
+	}
+	fi = make([]os.FileInfo, 0, len(seen))
+	for _, f := range seen {
+		fi = append(fi, f)
+	}
+	sort.Slice(fi, func(i, j int) bool { return fi[i].Name() < fi[j].Name() })
+	return
+}
+
+// Create creates name on the upper layer, clearing any whiteout
+// recorded for it
+func (o *OverlayFS) Create(name string) (f File, e error) {
+	delete(o.whiteout, name)
+	return o.upper.Create(name)
+}
+
+// Rename copies name up from the lower layer if needed, renames it on
+// the upper layer, and records old as whited out so the lower's
+// content stops showing through at that path
+func (o *OverlayFS) Rename(old, new string) (e error) {
+	if e = o.copyUp(old); e != nil {
+		return
+	}
+	if e = o.upper.Rename(old, new); e != nil {
+		return
+	}
+	o.whiteout[old] = true
+	delete(o.whiteout, new)
+	return
+}
+
+// OpenFile opens name for reading from either layer, or for writing
+// by copying it up from the lower layer first and opening it on the
+// upper layer
+func (o *OverlayFS) OpenFile(name string, flag int,
+	perm os.FileMode) (f File, e error) {
+	write := flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0
+	if write {
+		if e = o.copyUp(name); e != nil {
+			return
+		}
+		if e = o.upper.MkdirAll(path.Dir(name), 0755); e != nil {
+			return
+		}
+		if f, e = o.upper.OpenFile(name, flag, perm); e == nil {
+			delete(o.whiteout, name)
+		}
+		return
+	}
+	if f, e = o.upper.OpenFile(name, flag, perm); e == nil {
+		return
+	}
+	if o.whiteout[name] {
+		e = &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		return
+	}
+	return o.lower.OpenFile(name, flag, perm)
+}
+
+// MkdirAll creates name, along with any necessary parents, on the
+// upper layer
+func (o *OverlayFS) MkdirAll(name string, perm os.FileMode) (e error) {
+	return o.upper.MkdirAll(name, perm)
+}
+
+// copyUp copies name from the lower layer into the upper layer the
+// first time it is written, leaving it untouched if it's already
+// present on the upper layer, whited out, or absent from the lower one
+func (o *OverlayFS) copyUp(name string) (e error) {
+	if _, e = o.upper.Stat(name); e == nil {
+		return
+	}
+	if o.whiteout[name] {
+		e = nil
+		return
+	}
+	bs, e := o.lower.ReadFile(name)
+	if e != nil {
+		if os.IsNotExist(e) {
+			e = nil
+		}
+		return
+	}
+	if e = o.upper.MkdirAll(path.Dir(name), 0755); e != nil {
+		return
+	}
+	f, e := o.upper.Create(name)
+	if e != nil {
+		return
+	}
+	defer f.Close()
+	_, e = f.Write(bs)
+	return
+}
+
+// Commit flushes every file staged on the upper layer into target,
+// recreating its directory structure. Deletions recorded as
+// whiteouts are not propagated, since FileSystem has no remove
+// operation.
+func (o *OverlayFS) Commit(target FileSystem) (e error) {
+	return o.commitDir(target, ".")
+}
+
+func (o *OverlayFS) commitDir(target FileSystem, dir string) (e error) {
+	fis, e := o.upper.ReadDir(dir)
+	if e != nil {
+		return
+	}
+	for _, fi := range fis {
+		p := path.Join(dir, fi.Name())
+		if fi.IsDir() {
+			if e = target.MkdirAll(p, 0755); e != nil {
+				return
+			}
+			if e = o.commitDir(target, p); e != nil {
+				return
+			}
+			continue
+		}
+		var bs []byte
+		if bs, e = o.upper.ReadFile(p); e != nil {
+			return
+		}
+		if e = target.MkdirAll(path.Dir(p), 0755); e != nil {
+			return
+		}
+		var f File
+		if f, e = target.Create(p); e != nil {
+			return
+		}
+		_, e = f.Write(bs)
+		if ce := f.Close(); e == nil {
+			e = ce
+		}
+		if e != nil {
+			return
+		}
+	}
+	return
+}
+
+var _ FileSystem = (*OverlayFS)(nil)
@@ -0,0 +1,142 @@
+package filesystem_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/lamg/filesystem"
+)
+
+func TestOverlayFSReadThrough(t *testing.T) {
+	lower := filesystem.NewBufferFS()
+	f, e := lower.Create("lower.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	f.Write([]byte("from lower"))
+	f.Close()
+
+	o := filesystem.NewOverlayFS(filesystem.NewBufferFS(), lower)
+	bs, e := o.ReadFile("lower.txt")
+	if e != nil || string(bs) != "from lower" {
+		t.Fatalf("ReadFile: e=%v bs=%q", e, bs)
+	}
+}
+
+func TestOverlayFSCopyUpOnWrite(t *testing.T) {
+	lower := filesystem.NewBufferFS()
+	f, e := lower.Create("shared.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	f.Write([]byte("original"))
+	f.Close()
+
+	upper := filesystem.NewBufferFS()
+	o := filesystem.NewOverlayFS(upper, lower)
+
+	h, e := o.OpenFile("shared.txt", os.O_WRONLY, 0644)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = h.Write([]byte("replaced")); e != nil {
+		t.Fatal(e)
+	}
+	if e = h.Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	bs, e := o.ReadFile("shared.txt")
+	if e != nil || string(bs) != "replaced" {
+		t.Fatalf("ReadFile(overlay): e=%v bs=%q", e, bs)
+	}
+
+	lowerBs, e := lower.ReadFile("shared.txt")
+	if e != nil || string(lowerBs) != "original" {
+		t.Fatalf("lower untouched by copy-up: e=%v bs=%q", e, lowerBs)
+	}
+
+	upperBs, e := upper.ReadFile("shared.txt")
+	if e != nil || string(upperBs) != "replaced" {
+		t.Fatalf("upper holds the copy-up: e=%v bs=%q", e, upperBs)
+	}
+}
+
+func TestOverlayFSWhiteout(t *testing.T) {
+	lower := filesystem.NewBufferFS()
+	f, e := lower.Create("old.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	f.Write([]byte("data"))
+	f.Close()
+
+	o := filesystem.NewOverlayFS(filesystem.NewBufferFS(), lower)
+	if e = o.Rename("old.txt", "new.txt"); e != nil {
+		t.Fatal(e)
+	}
+
+	if _, e = o.Stat("old.txt"); !errors.Is(e, os.ErrNotExist) {
+		t.Fatalf("Stat(old): expected ErrNotExist, got %v", e)
+	}
+	bs, e := o.ReadFile("new.txt")
+	if e != nil || string(bs) != "data" {
+		t.Fatalf("ReadFile(new): e=%v bs=%q", e, bs)
+	}
+}
+
+func TestOverlayFSCreateStaysOnUpper(t *testing.T) {
+	lower := filesystem.NewBufferFS()
+	upper := filesystem.NewBufferFS()
+	o := filesystem.NewOverlayFS(upper, lower)
+
+	f, e := o.Create("new.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	f.Write([]byte("fresh"))
+	f.Close()
+
+	if _, e = lower.Stat("new.txt"); !errors.Is(e, os.ErrNotExist) {
+		t.Fatalf("lower should not see new.txt, got e=%v", e)
+	}
+	bs, e := upper.ReadFile("new.txt")
+	if e != nil || string(bs) != "fresh" {
+		t.Fatalf("upper.ReadFile: e=%v bs=%q", e, bs)
+	}
+}
+
+func TestOverlayFSCommit(t *testing.T) {
+	lower := filesystem.NewBufferFS()
+	f, e := lower.Create("base.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	f.Write([]byte("base"))
+	f.Close()
+
+	o := filesystem.NewOverlayFS(filesystem.NewBufferFS(), lower)
+	if e = o.MkdirAll("dir", 0755); e != nil {
+		t.Fatal(e)
+	}
+	nf, e := o.Create("dir/new.txt")
+	if e != nil {
+		t.Fatal(e)
+	}
+	nf.Write([]byte("new"))
+	nf.Close()
+
+	target := filesystem.NewBufferFS()
+	if e = o.Commit(target); e != nil {
+		t.Fatal(e)
+	}
+
+	bs, e := target.ReadFile("dir/new.txt")
+	if e != nil || string(bs) != "new" {
+		t.Fatalf("target.ReadFile(dir/new.txt): e=%v bs=%q", e, bs)
+	}
+	if _, e = target.Stat("base.txt"); !errors.Is(e, os.ErrNotExist) {
+		t.Fatalf("Commit should not propagate lower-only files, got e=%v", e)
+	}
+}